@@ -0,0 +1,670 @@
+package dialog
+
+// Backend abstracts how a widget is actually rendered. ExecBackend shells
+// out to the 'dialog' binary (the historical, and still default, behavior).
+// NativeBackend draws the same widgets itself with tcell, so callers don't
+// need 'dialog' installed and tests can run without a TTY.
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// Backend is implemented once per rendering strategy. Each method mirrors
+// one of the exported widget types and returns dialog's raw string result
+// (a menu key, an input value, checklist tags, ...) plus any error. ctx
+// lets a caller abandon a widget early -- ExecBackend kills the underlying
+// 'dialog' process via exec.CommandContext; NativeBackend stops polling for
+// input and returns ctx.Err().
+type Backend interface {
+	MsgBox(ctx context.Context, c *Common, text string) error
+	Pause(ctx context.Context, c *Common, crumbs, text string, seconds int) error
+	Menu(ctx context.Context, c *Common, crumbs, text string, menuHeight int, defaultKey string, opts []MenuOption) (string, error)
+	InputBox(ctx context.Context, c *Common, crumbs, text, value string) (string, error)
+	CheckListBox(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem) (string, error)
+	ProgramBox(ctx context.Context, c *Common, crumbs, text string, updates <-chan ProgressUpdate) (string, error)
+	Confirm(ctx context.Context, c *Common, crumbs, text string, defaultYes bool) (bool, error)
+	Password(ctx context.Context, c *Common, crumbs, text string) (string, error)
+	MultiSelect(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem, filterable bool) (string, error)
+	Editor(ctx context.Context, c *Common, crumbs, text, value string) (string, error)
+	MixedForm(ctx context.Context, c *Common, crumbs, text string, formHeight int, items []MixedFormItem) (string, error)
+}
+
+// defaultBackend is used by any Common that doesn't set one explicitly, so
+// existing callers keep shelling out to 'dialog' unchanged.
+var defaultBackend Backend = ExecBackend{}
+
+func (c *Common) backend() Backend {
+	if c.Backend != nil {
+		return c.Backend
+	}
+	return defaultBackend
+}
+
+// ExecBackend renders widgets by invoking the external 'dialog' process,
+// the same way this package always has.
+type ExecBackend struct{}
+
+func (ExecBackend) MsgBox(ctx context.Context, c *Common, text string) error {
+	args := c.runArgs()
+	args = append(args,
+		"--msgbox", text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()))
+	_, err := run(ctx, args)
+	return err
+}
+
+func (ExecBackend) Pause(ctx context.Context, c *Common, crumbs, text string, seconds int) error {
+	args := c.runArgs()
+	args = append(args,
+		"--pause", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()),
+		strconv.Itoa(seconds))
+	_, err := run(ctx, args)
+	return err
+}
+
+func (ExecBackend) Menu(ctx context.Context, c *Common, crumbs, text string, menuHeight int, defaultKey string, opts []MenuOption) (string, error) {
+	args := c.runArgs()
+	if defaultKey != "" {
+		args = append(args, "--default-item", defaultKey)
+	}
+	args = append(args,
+		"--menu", text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()),
+		strconv.Itoa(menuHeight))
+	for _, mo := range opts {
+		args = append(args, mo.Key, mo.Text)
+	}
+	return run(ctx, args)
+}
+
+func (ExecBackend) InputBox(ctx context.Context, c *Common, crumbs, text, value string) (string, error) {
+	args := c.runArgs()
+	args = append(args,
+		"--inputbox", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()),
+		value)
+	return run(ctx, args)
+}
+
+func (ExecBackend) CheckListBox(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem) (string, error) {
+	args := c.runArgs()
+	args = append(args,
+		"--checklist", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()),
+		strconv.Itoa(len(items)))
+	args = append(args, checkListItemArgs(items)...)
+	return run(ctx, args)
+}
+
+// ProgramBox picks its dialog widget from the first update it sees, since
+// dialog can't switch box types mid-process: a Task name means
+// --mixedgauge, a bare Percent (no Log) means --gauge, anything else
+// streams as a --programbox log.
+func (ExecBackend) ProgramBox(ctx context.Context, c *Common, crumbs, text string, updates <-chan ProgressUpdate) (string, error) {
+	first, ok := <-updates
+	if !ok {
+		return ExecBackend{}.runProgramBoxLog(ctx, c, crumbs, text, ProgressUpdate{}, updates)
+	}
+	switch {
+	case first.Task != "":
+		return ExecBackend{}.runMixedGauge(ctx, c, crumbs, text, first, updates)
+	case first.Log == "":
+		return ExecBackend{}.runGauge(ctx, c, crumbs, text, first, updates)
+	default:
+		return ExecBackend{}.runProgramBoxLog(ctx, c, crumbs, text, first, updates)
+	}
+}
+
+func (ExecBackend) runGauge(ctx context.Context, c *Common, crumbs, text string, first ProgressUpdate, updates <-chan ProgressUpdate) (string, error) {
+	percent := 0
+	if first.Percent >= 0 {
+		percent = clampPercent(first.Percent)
+	}
+	piperd, pipewr := io.Pipe()
+	go func() {
+		defer pipewr.Close()
+		writeGaugeUpdate(pipewr, first, &percent)
+		for u := range updates {
+			writeGaugeUpdate(pipewr, u, &percent)
+		}
+	}()
+	args := c.runArgs()
+	args = append(args,
+		"--gauge", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()),
+		strconv.Itoa(percent))
+	return runStdin(ctx, args, piperd)
+}
+
+// writeGaugeUpdate writes u to dialog's --gauge stdin protocol. A negative
+// u.Percent means this update carries no overall percent (a status-only or
+// log-only report), so it reuses *last instead of dragging the bar to 0.
+func writeGaugeUpdate(w io.Writer, u ProgressUpdate, last *int) {
+	if u.Percent >= 0 {
+		*last = clampPercent(u.Percent)
+	}
+	if u.Status != "" {
+		fmt.Fprintf(w, "XXX\n%d\n%s\nXXX\n", *last, u.Status)
+	} else if u.Percent >= 0 {
+		fmt.Fprintf(w, "%d\n", *last)
+	}
+}
+
+// runMixedGauge redraws dialog --mixedgauge on every update, since unlike
+// --gauge it has no stdin streaming protocol of its own.
+func (ExecBackend) runMixedGauge(ctx context.Context, c *Common, crumbs, text string, first ProgressUpdate, updates <-chan ProgressUpdate) (string, error) {
+	tasks := map[string]int{}
+	order := []string{}
+	overall := 0
+	// apply folds u into tasks/overall. A negative u.Percent means this
+	// update carries no overall percent (a status-only report), so it
+	// leaves whatever percent is already known for u.Task alone instead of
+	// dragging its mini-gauge to 0.
+	apply := func(u ProgressUpdate) {
+		if _, seen := tasks[u.Task]; !seen {
+			order = append(order, u.Task)
+			tasks[u.Task] = 0
+		}
+		if u.Percent < 0 {
+			return
+		}
+		tasks[u.Task] = clampPercent(u.Percent)
+		if u.Task == "" {
+			overall = clampPercent(u.Percent)
+		}
+	}
+	apply(first)
+
+	var out string
+	var lastErr error
+	redraw := func() {
+		args := c.runArgs()
+		args = append(args,
+			"--mixedgauge", crumbs+"\\n"+text,
+			strconv.Itoa(c.height()),
+			strconv.Itoa(c.width()),
+			strconv.Itoa(overall))
+		for _, name := range order {
+			if name == "" {
+				continue
+			}
+			// dialog reads 0-9 as symbolic item statuses (0=Succeeded ...
+			// 8=N/A) and anything else as invalid; a percentage mini-gauge
+			// has to be passed negated.
+			args = append(args, name, strconv.Itoa(-tasks[name]))
+		}
+		out, lastErr = run(ctx, args)
+	}
+	redraw()
+	for u := range updates {
+		apply(u)
+		redraw()
+	}
+	return out, lastErr
+}
+
+func (ExecBackend) runProgramBoxLog(ctx context.Context, c *Common, crumbs, text string, first ProgressUpdate, updates <-chan ProgressUpdate) (string, error) {
+	piperd, pipewr := io.Pipe()
+	go func() {
+		defer pipewr.Close()
+		writeLogUpdate(pipewr, first)
+		for u := range updates {
+			writeLogUpdate(pipewr, u)
+		}
+	}()
+	args := c.runArgs()
+	args = append(args,
+		"--programbox", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()))
+	return runStdin(ctx, args, piperd)
+}
+
+func writeLogUpdate(w io.Writer, u ProgressUpdate) {
+	if u.Log != "" {
+		fmt.Fprintln(w, u.Log)
+	} else if u.Status != "" {
+		fmt.Fprintln(w, u.Status)
+	}
+}
+
+func (ExecBackend) Confirm(ctx context.Context, c *Common, crumbs, text string, defaultYes bool) (bool, error) {
+	args := c.runArgs()
+	if !defaultYes {
+		args = append(args, "--defaultno")
+	}
+	args = append(args,
+		"--yesno", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()))
+	_, err := run(ctx, args)
+	if err == nil {
+		return true, nil
+	}
+	if de, ok := err.(*DialogError); ok && de.IsCancel() {
+		return false, nil
+	}
+	return false, err
+}
+
+// Password uses dialog's --insecure so the typed characters show as stars
+// rather than being completely hidden; the result is still never echoed to
+// stdout/logs, only returned to the caller.
+func (ExecBackend) Password(ctx context.Context, c *Common, crumbs, text string) (string, error) {
+	args := c.runArgs()
+	args = append(args,
+		"--insecure",
+		"--passwordbox", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()))
+	return run(ctx, args)
+}
+
+// MultiSelect falls back to a plain --checklist: the 'dialog' binary has no
+// live filter-as-you-type, so filterable is only honored by NativeBackend.
+func (ExecBackend) MultiSelect(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem, filterable bool) (string, error) {
+	return ExecBackend{}.CheckListBox(ctx, c, crumbs, text, items)
+}
+
+func (ExecBackend) Editor(ctx context.Context, c *Common, crumbs, text, value string) (string, error) {
+	return runEditor(ctx, value)
+}
+
+func (ExecBackend) MixedForm(ctx context.Context, c *Common, crumbs, text string, formHeight int, items []MixedFormItem) (string, error) {
+	args := c.runArgs()
+	args = append(args,
+		"--mixedform", crumbs+"\\n"+text,
+		strconv.Itoa(c.height()),
+		strconv.Itoa(c.width()),
+		strconv.Itoa(formHeight))
+	args = append(args, mixedFormItemArgs(items)...)
+	return run(ctx, args)
+}
+
+// NativeBackend draws widgets directly on the terminal with tcell, with no
+// dependency on the 'dialog' binary being installed.
+type NativeBackend struct{}
+
+func (NativeBackend) screen() (tcell.Screen, error) {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (n NativeBackend) MsgBox(ctx context.Context, c *Common, text string) error {
+	s, err := n.screen()
+	if err != nil {
+		return err
+	}
+	defer s.Fini()
+	drawBox(s, c.title(), text)
+	_, err = waitForKeyCtx(ctx, s, tcell.KeyEnter, tcell.KeyEscape)
+	return err
+}
+
+func (n NativeBackend) Pause(ctx context.Context, c *Common, crumbs, text string, seconds int) error {
+	s, err := n.screen()
+	if err != nil {
+		return err
+	}
+	defer s.Fini()
+	drawBox(s, c.title(), crumbs+"\n"+text)
+	return sleepOrKeyCtx(ctx, s, seconds)
+}
+
+func (n NativeBackend) Menu(ctx context.Context, c *Common, crumbs, text string, menuHeight int, defaultKey string, opts []MenuOption) (string, error) {
+	s, err := n.screen()
+	if err != nil {
+		return "", err
+	}
+	defer s.Fini()
+
+	selected := 0
+	for i, mo := range opts {
+		if mo.Key == defaultKey {
+			selected = i
+		}
+	}
+	for {
+		drawMenu(s, c.title(), text, opts, selected)
+		k, err := readKeyCtx(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		switch k {
+		case tcell.KeyUp:
+			if selected > 0 {
+				selected--
+			}
+		case tcell.KeyDown:
+			if selected < len(opts)-1 {
+				selected++
+			}
+		case tcell.KeyEnter:
+			return opts[selected].Key, nil
+		case tcell.KeyEscape:
+			return "", &DialogError{Code: ExitEsc}
+		case tcell.KeyCtrlC:
+			return "", &DialogError{Code: ExitCancel}
+		}
+	}
+}
+
+func (n NativeBackend) InputBox(ctx context.Context, c *Common, crumbs, text, value string) (string, error) {
+	s, err := n.screen()
+	if err != nil {
+		return "", err
+	}
+	defer s.Fini()
+
+	buf := []rune(value)
+	for {
+		drawInput(s, c.title(), crumbs+"\n"+text, string(buf))
+		ev, err := pollEventCtx(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		if kev, ok := ev.(*tcell.EventKey); ok {
+			switch kev.Key() {
+			case tcell.KeyEnter:
+				return string(buf), nil
+			case tcell.KeyEscape:
+				return "", &DialogError{Code: ExitEsc}
+			case tcell.KeyCtrlC:
+				return "", &DialogError{Code: ExitCancel}
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(buf) > 0 {
+					buf = buf[:len(buf)-1]
+				}
+			case tcell.KeyRune:
+				buf = append(buf, kev.Rune())
+			}
+		}
+	}
+}
+
+func (n NativeBackend) CheckListBox(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem) (string, error) {
+	s, err := n.screen()
+	if err != nil {
+		return "", err
+	}
+	defer s.Fini()
+
+	cursor := 0
+	checked := make([]bool, len(items))
+	for i, it := range items {
+		checked[i] = *it.Value
+	}
+	for {
+		drawCheckList(s, c.title(), crumbs+"\n"+text, items, checked, cursor)
+		k, err := readKeyCtx(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		switch k {
+		case tcell.KeyUp:
+			if cursor > 0 {
+				cursor--
+			}
+		case tcell.KeyDown:
+			if cursor < len(items)-1 {
+				cursor++
+			}
+		case tcell.KeyRune:
+			checked[cursor] = !checked[cursor]
+		case tcell.KeyEnter:
+			out := ""
+			for i, on := range checked {
+				if on {
+					out += strconv.Itoa(i) + " "
+				}
+			}
+			return out, nil
+		case tcell.KeyEscape:
+			return "", &DialogError{Code: ExitEsc}
+		case tcell.KeyCtrlC:
+			return "", &DialogError{Code: ExitCancel}
+		}
+	}
+}
+
+func (n NativeBackend) ProgramBox(ctx context.Context, c *Common, crumbs, text string, updates <-chan ProgressUpdate) (string, error) {
+	s, err := n.screen()
+	if err != nil {
+		return "", err
+	}
+	defer s.Fini()
+
+	tasks := map[string]int{}
+	order := []string{}
+	overall := 0
+	logLines := []string{}
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case u, ok := <-updates:
+			if !ok {
+				return "", nil
+			}
+			if u.Task != "" {
+				if _, seen := tasks[u.Task]; !seen {
+					order = append(order, u.Task)
+					tasks[u.Task] = 0
+				}
+				if u.Percent >= 0 {
+					tasks[u.Task] = clampPercent(u.Percent)
+				}
+			} else if u.Percent >= 0 {
+				overall = clampPercent(u.Percent)
+			}
+			if u.Log != "" {
+				logLines = append(logLines, u.Log)
+			}
+			drawProgress(s, c.title(), crumbs+"\n"+text, overall, order, tasks, logLines)
+		}
+	}
+}
+
+func (n NativeBackend) Confirm(ctx context.Context, c *Common, crumbs, text string, defaultYes bool) (bool, error) {
+	s, err := n.screen()
+	if err != nil {
+		return false, err
+	}
+	defer s.Fini()
+
+	yes := defaultYes
+	for {
+		drawConfirm(s, c.title(), crumbs+"\n"+text, yes)
+		k, err := readKeyCtx(ctx, s)
+		if err != nil {
+			return false, err
+		}
+		switch k {
+		case tcell.KeyLeft, tcell.KeyRight, tcell.KeyTab:
+			yes = !yes
+		case tcell.KeyEnter:
+			return yes, nil
+		case tcell.KeyEscape:
+			return false, &DialogError{Code: ExitEsc}
+		case tcell.KeyCtrlC:
+			return false, &DialogError{Code: ExitCancel}
+		}
+	}
+}
+
+func (n NativeBackend) Password(ctx context.Context, c *Common, crumbs, text string) (string, error) {
+	s, err := n.screen()
+	if err != nil {
+		return "", err
+	}
+	defer s.Fini()
+
+	buf := []rune{}
+	for {
+		drawInput(s, c.title(), crumbs+"\n"+text, strings.Repeat("*", len(buf)))
+		ev, err := pollEventCtx(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		if kev, ok := ev.(*tcell.EventKey); ok {
+			switch kev.Key() {
+			case tcell.KeyEnter:
+				return string(buf), nil
+			case tcell.KeyEscape:
+				return "", &DialogError{Code: ExitEsc}
+			case tcell.KeyCtrlC:
+				return "", &DialogError{Code: ExitCancel}
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(buf) > 0 {
+					buf = buf[:len(buf)-1]
+				}
+			case tcell.KeyRune:
+				buf = append(buf, kev.Rune())
+			}
+		}
+	}
+}
+
+func (n NativeBackend) MultiSelect(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem, filterable bool) (string, error) {
+	s, err := n.screen()
+	if err != nil {
+		return "", err
+	}
+	defer s.Fini()
+
+	cursor := 0
+	checked := make([]bool, len(items))
+	for i, it := range items {
+		checked[i] = *it.Value
+	}
+	filter := ""
+	for {
+		visible := visibleIndices(items, filter)
+		if cursor >= len(visible) {
+			cursor = len(visible) - 1
+		}
+		if cursor < 0 {
+			cursor = 0
+		}
+		drawMultiSelect(s, c.title(), crumbs+"\n"+text, items, checked, visible, cursor, filter)
+		ev, err := pollEventCtx(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		kev, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch kev.Key() {
+		case tcell.KeyUp:
+			if cursor > 0 {
+				cursor--
+			}
+		case tcell.KeyDown:
+			if cursor < len(visible)-1 {
+				cursor++
+			}
+		case tcell.KeyCtrlSpace:
+			if len(visible) > 0 {
+				checked[visible[cursor]] = !checked[visible[cursor]]
+			}
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if filterable && len(filter) > 0 {
+				filter = filter[:len(filter)-1]
+			}
+		case tcell.KeyEnter:
+			out := ""
+			for i, on := range checked {
+				if on {
+					out += strconv.Itoa(i) + " "
+				}
+			}
+			return out, nil
+		case tcell.KeyEscape:
+			return "", &DialogError{Code: ExitEsc}
+		case tcell.KeyCtrlC:
+			return "", &DialogError{Code: ExitCancel}
+		case tcell.KeyRune:
+			if filterable {
+				filter += string(kev.Rune())
+			} else if len(visible) > 0 {
+				checked[visible[cursor]] = !checked[visible[cursor]]
+			}
+		}
+	}
+}
+
+func (n NativeBackend) Editor(ctx context.Context, c *Common, crumbs, text, value string) (string, error) {
+	return runEditor(ctx, value)
+}
+
+func (n NativeBackend) MixedForm(ctx context.Context, c *Common, crumbs, text string, formHeight int, items []MixedFormItem) (string, error) {
+	s, err := n.screen()
+	if err != nil {
+		return "", err
+	}
+	defer s.Fini()
+
+	values := make([]string, len(items))
+	for i, it := range items {
+		values[i] = *it.Value
+	}
+	cursor := 0
+	for {
+		drawMixedForm(s, c.title(), crumbs+"\n"+text, items, values, cursor)
+		ev, err := pollEventCtx(ctx, s)
+		if err != nil {
+			return "", err
+		}
+		kev, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch kev.Key() {
+		case tcell.KeyTab, tcell.KeyDown:
+			cursor = nextFormField(len(items), cursor, 1)
+		case tcell.KeyUp:
+			cursor = nextFormField(len(items), cursor, -1)
+		case tcell.KeyEnter:
+			return strings.Join(values, "\n"), nil
+		case tcell.KeyEscape:
+			return "", &DialogError{Code: ExitEsc}
+		case tcell.KeyCtrlC:
+			return "", &DialogError{Code: ExitCancel}
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if items[cursor].IType != 1 && len(values[cursor]) > 0 {
+				values[cursor] = values[cursor][:len(values[cursor])-1]
+			}
+		case tcell.KeyRune:
+			if items[cursor].IType != 1 {
+				values[cursor] += string(kev.Rune())
+			}
+		}
+	}
+}
+
+func nextFormField(n, cur, dir int) int {
+	if n == 0 {
+		return 0
+	}
+	return ((cur+dir)%n + n) % n
+}
@@ -0,0 +1,25 @@
+package dialog
+
+import "testing"
+
+func TestNextFormField(t *testing.T) {
+	tests := []struct {
+		name        string
+		n, cur, dir int
+		want        int
+	}{
+		{"advances forward", 3, 0, 1, 1},
+		{"wraps forward past the end", 3, 2, 1, 0},
+		{"steps backward", 3, 1, -1, 0},
+		{"wraps backward past the start", 3, 0, -1, 2},
+		{"single field stays put", 1, 0, 1, 0},
+		{"zero fields returns zero", 0, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextFormField(tt.n, tt.cur, tt.dir); got != tt.want {
+				t.Errorf("nextFormField(%d, %d, %d) = %d, want %d", tt.n, tt.cur, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
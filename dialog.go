@@ -4,6 +4,7 @@ package dialog
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -16,10 +17,11 @@ import (
 )
 
 const (
-	DIALOG_PROC         = "dialog"
-	DEFAULT_HEIGHT      = 20
-	DEFAULT_WIDTH       = 60
-	DEFAULT_MENU_HEIGHT = 15
+	DIALOG_PROC          = "dialog"
+	DEFAULT_HEIGHT       = 20
+	DEFAULT_WIDTH        = 60
+	DEFAULT_MENU_HEIGHT  = 15
+	DEFAULT_MAX_ATTEMPTS = 3
 )
 
 var (
@@ -27,14 +29,14 @@ var (
 	ErrorDialogRc = "/etc/error.dialogrc"
 )
 
-func run(args []string) (string, error) {
-	return runStdin(args, os.Stdin)
+func run(ctx context.Context, args []string) (string, error) {
+	return runStdin(ctx, args, os.Stdin)
 }
 
-func runStdin(args []string, stdin io.Reader) (string, error) {
+func runStdin(ctx context.Context, args []string, stdin io.Reader) (string, error) {
 	choiceOutput := &bytes.Buffer{}
 	cmdArgs := append([]string{"--output-fd", "3"}, args...)
-	cmd := exec.Command(DIALOG_PROC, cmdArgs...)
+	cmd := exec.CommandContext(ctx, DIALOG_PROC, cmdArgs...)
 	cmd.Stdin = stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -60,7 +62,13 @@ func runStdin(args []string, stdin io.Reader) (string, error) {
 	choice := string(choiceOutput.Bytes())
 	//fmt.Fprintf(os.Stdout, "args %#v choice is %v err %v\n", cmdArgs, choice, err)
 	//fmt.Fprintf(os.Stderr, "args %#v choice is %v err %v\n", cmdArgs, choice, err)
-	return choice, err
+	if err != nil {
+		if code, ok := exitCode(err); ok {
+			return choice, &DialogError{Code: code}
+		}
+		return choice, err
+	}
+	return choice, nil
 }
 
 type ChildDialog struct {
@@ -70,8 +78,12 @@ type ChildDialog struct {
 	MenuOption *MenuOption
 }
 
+// Dialog is one widget in a tree. Run renders it and returns the next
+// dialog to run (a new child, a sibling, or nil to stop), or an error.
+// ctx lets a caller cancel a subtree; the underlying 'dialog' process (if
+// any) is killed via exec.CommandContext.
 type Dialog interface {
-	Run(string) (Dialog, error) // new child dialog, new sibling dialog, error.
+	Run(ctx context.Context, crumbs string) (Dialog, error)
 }
 
 type Common struct {
@@ -79,6 +91,41 @@ type Common struct {
 	Title    string
 	Width    int
 	Height   int
+	// Backend picks how widgets are rendered. Nil means ExecBackend, i.e.
+	// the historical behavior of shelling out to the 'dialog' binary.
+	Backend Backend
+	// Key identifies this widget within a Runner's answers file. It has no
+	// effect on ExecBackend or NativeBackend rendering.
+	Key string
+	// MaxAttempts caps how many times the widget is shown, the first prompt
+	// included, while Validate keeps failing; after that many rejected
+	// attempts it gives up with a ValidationError. <= 0 means DEFAULT_MAX_ATTEMPTS.
+	MaxAttempts int
+}
+
+func (c *Common) maxAttempts() int {
+	if c.MaxAttempts > 0 {
+		return c.MaxAttempts
+	}
+	return DEFAULT_MAX_ATTEMPTS
+}
+
+// ValidationError is returned by InputBox or CheckListBox when Validate
+// keeps rejecting the user's input for MaxAttempts attempts in total, so
+// callers can distinguish this from a user cancel or a backend failure.
+type ValidationError struct {
+	Message  string
+	Attempts int
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("dialog: validation failed after %d attempt(s): %s", e.Attempts, e.Message)
+}
+
+// setBackend lets a Runner take over rendering for a widget it drives,
+// regardless of what the caller already set on Common.Backend.
+func (c *Common) setBackend(b Backend) {
+	c.Backend = b
 }
 
 func (c *Common) title() string {
@@ -121,15 +168,8 @@ type MsgBox struct {
 	NextSibling Dialog
 }
 
-func (m *MsgBox) Run(crumbs string) (Dialog, error) {
-	args := m.Common.runArgs()
-	args = append(args,
-		"--msgbox", m.Text,
-		strconv.Itoa(m.height()),
-		strconv.Itoa(m.width()))
-
-	_, err := run(args)
-	if err != nil {
+func (m *MsgBox) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	if err := m.Common.backend().MsgBox(ctx, &m.Common, m.Text); err != nil {
 		return nil, err
 	}
 	return m.NextSibling, nil
@@ -142,16 +182,8 @@ type Pause struct {
 	NextSibling Dialog
 }
 
-func (m *Pause) Run(crumbs string) (Dialog, error) {
-	args := m.Common.runArgs()
-	args = append(args,
-		"--pause", crumbs+"\\n"+m.Text,
-		strconv.Itoa(m.height()),
-		strconv.Itoa(m.width()),
-		strconv.Itoa(m.Seconds))
-
-	_, err := run(args)
-	if err != nil {
+func (m *Pause) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	if err := m.Common.backend().Pause(ctx, &m.Common, crumbs, m.Text, m.Seconds); err != nil {
 		return nil, err
 	}
 	return m.NextSibling, nil
@@ -163,6 +195,10 @@ type Menu struct {
 	MenuHeight int
 	DefaultKey string
 	Options    func() ([]MenuOption, error)
+	// Parent is returned, with no error, when the user cancels out of this
+	// menu (dialog's Cancel button) -- "go up one level". ESC aborts the
+	// whole tree instead and is returned as a *DialogError.
+	Parent Dialog
 }
 
 type MenuOption struct {
@@ -180,30 +216,21 @@ func (m *Menu) menuHeight(optlen int) int {
 	return optlen
 }
 
-func (m *Menu) Run(crumbs string) (Dialog, error) {
+func (m *Menu) Run(ctx context.Context, crumbs string) (Dialog, error) {
 	opts, err := m.Options()
 	if err != nil {
 		return nil, err
 	}
 
-	args := m.Common.runArgs()
-	if m.DefaultKey != "" {
-		args = append(args, "--default-item", m.DefaultKey)
-	}
 	text := crumbs
 	if m.Text != nil {
 		text = text + "\\n" + m.Text()
 	}
-	args = append(args,
-		"--menu", text,
-		strconv.Itoa(m.height()),
-		strconv.Itoa(m.width()),
-		strconv.Itoa(m.menuHeight(len(opts))))
-	for _, mo := range opts {
-		args = append(args, mo.Key, mo.Text)
-	}
-	k, err := run(args)
+	k, err := m.Common.backend().Menu(ctx, &m.Common, crumbs, text, m.menuHeight(len(opts)), m.DefaultKey, opts)
 	if err != nil {
+		if de, ok := err.(*DialogError); ok && de.IsCancel() {
+			return m.Parent, nil
+		}
 		return nil, err
 	}
 	for _, mo := range opts {
@@ -222,42 +249,50 @@ func (m *Menu) Run(crumbs string) (Dialog, error) {
 
 type InputBox struct {
 	Common
-	Text        func() string
-	Value       *string
+	Text  func() string
+	Value *string
+	// Validate returns (message to show on rejection, ok). The message is
+	// ignored when ok is true.
 	Validate    func(string) (string, bool)
 	NextSibling Dialog
 }
 
-func (m *InputBox) Run(crumbs string) (Dialog, error) {
+func (m *InputBox) Run(ctx context.Context, crumbs string) (Dialog, error) {
 	if m.Value == nil {
 		return nil, fmt.Errorf("inputbox has no result ptr")
 	}
 	if m.Text == nil {
 		return nil, fmt.Errorf("inputbox has no text func")
 	}
-	args := m.Common.runArgs()
-	args = append(args,
-		"--inputbox", crumbs+"\\n"+m.Text(),
-		strconv.Itoa(m.height()),
-		strconv.Itoa(m.width()),
-		*m.Value)
-	k, err := run(args)
-	if err != nil {
-		return nil, err
-	} else if m.Validate != nil {
-		_, ok := m.Validate(k)
-		if !ok {
-			// TODO FIXME: flash error, return new sibling
+	value := *m.Value
+	for attempt := 1; ; attempt++ {
+		k, err := m.Common.backend().InputBox(ctx, &m.Common, crumbs, m.Text(), value)
+		if err != nil {
+			return nil, err
+		}
+		if m.Validate != nil {
+			if msg, ok := m.Validate(k); !ok {
+				value = k
+				if attempt >= m.Common.maxAttempts() {
+					return nil, &ValidationError{Message: msg, Attempts: attempt}
+				}
+				if err := m.Common.backend().MsgBox(ctx, &m.Common, msg); err != nil {
+					return nil, err
+				}
+				continue
+			}
 		}
+		*m.Value = k
+		return m.NextSibling, nil
 	}
-	*m.Value = k
-	return m.NextSibling, nil
 }
 
 type CheckListBox struct {
 	Common
-	Text        func() string
-	Items       []CheckListItem
+	Text  func() string
+	Items []CheckListItem
+	// Validate returns (message to show on rejection, ok), given dialog's
+	// raw space-separated tag output.
 	Validate    func(string) (string, bool)
 	NextSibling Dialog
 }
@@ -267,9 +302,21 @@ type CheckListItem struct {
 	Value *bool
 }
 
-func (m *CheckListBox) itemArgs() []string {
+// parseCheckListResult turns dialog's space-separated tag output (or a
+// backend's equivalent) into a set of selected item indices.
+func parseCheckListResult(k string, n int) map[int]bool {
+	setIndices := map[int]bool{}
+	for _, v := range regexp.MustCompile("\\s+").Split(strings.TrimSpace(k), -1) {
+		if vi, err := strconv.Atoi(v); err == nil && vi >= 0 && vi < n {
+			setIndices[vi] = true
+		}
+	}
+	return setIndices
+}
+
+func checkListItemArgs(items []CheckListItem) []string {
 	ret := []string{}
-	for i, item := range m.Items {
+	for i, item := range items {
 		// tag
 		ret = append(ret, strconv.Itoa(i))
 		// item
@@ -284,7 +331,7 @@ func (m *CheckListBox) itemArgs() []string {
 	return ret
 }
 
-func (m *CheckListBox) Run(crumbs string) (Dialog, error) {
+func (m *CheckListBox) Run(ctx context.Context, crumbs string) (Dialog, error) {
 	for _, item := range m.Items {
 		if item.Value == nil {
 			return nil, fmt.Errorf("checklistbox has no result ptr")
@@ -293,74 +340,126 @@ func (m *CheckListBox) Run(crumbs string) (Dialog, error) {
 	if m.Text == nil {
 		return nil, fmt.Errorf("checklistbox has no text func")
 	}
-	args := m.Common.runArgs()
-	args = append(args,
-		"--checklist", crumbs+"\\n"+m.Text(),
-		strconv.Itoa(m.height()),
-		strconv.Itoa(m.width()),
-		strconv.Itoa(len(m.Items)))
-	args = append(args, m.itemArgs()...)
-	k, err := run(args)
-	if err != nil {
-		return nil, err
-	} else if m.Validate != nil {
-		_, ok := m.Validate(k)
-		if !ok {
-			// TODO FIXME: flash error, return new sibling
-		}
+	values := make([]bool, len(m.Items))
+	shadow := make([]CheckListItem, len(m.Items))
+	for i, item := range m.Items {
+		values[i] = *item.Value
+		shadow[i] = CheckListItem{Name: item.Name, Value: &values[i]}
 	}
-
-	// parse returned values
-	setIndices := map[int]bool{}
-	for _, v := range regexp.MustCompile("\\s+").Split(strings.TrimSpace(k), -1) {
-		if vi, err := strconv.Atoi(v); err == nil && vi >= 0 && vi < len(m.Items) {
-			setIndices[vi] = true
+	for attempt := 1; ; attempt++ {
+		k, err := m.Common.backend().CheckListBox(ctx, &m.Common, crumbs, m.Text(), shadow)
+		if err != nil {
+			return nil, err
 		}
+		setIndices := parseCheckListResult(k, len(shadow))
+		for i := range shadow {
+			values[i] = setIndices[i]
+		}
+		if m.Validate != nil {
+			if msg, ok := m.Validate(k); !ok {
+				if attempt >= m.Common.maxAttempts() {
+					return nil, &ValidationError{Message: msg, Attempts: attempt}
+				}
+				if err := m.Common.backend().MsgBox(ctx, &m.Common, msg); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		for i, item := range m.Items {
+			*item.Value = values[i]
+		}
+		return m.NextSibling, nil
 	}
-	for i, item := range m.Items {
-		*item.Value = setIndices[i]
-	}
-
-	return m.NextSibling, nil
 }
 
 type MixedForm struct {
-	Text       string
-	FormHeight int
-	Items      []MixedFormItem
+	Common
+	Text        func() string
+	FormHeight  int
+	Items       []MixedFormItem
+	NextSibling Dialog
 }
 
+// MixedFormItem is one label+field row of a --mixedform. IType controls how
+// the field behaves: 0 normal, 1 readonly, 2 hidden (password-style).
 type MixedFormItem struct {
 	Label string
+	Y, X  int
+	FLen  int
+	ILen  int
+	IType int
+	Value *string
 }
 
-//--mixedform text height width formheight [ label y x item y x flen ilen itype ]
+func (m *MixedForm) formHeight() int {
+	if m.FormHeight > 0 {
+		return m.FormHeight
+	}
+	return len(m.Items)
+}
 
+func (m *MixedForm) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	for _, it := range m.Items {
+		if it.Value == nil {
+			return nil, fmt.Errorf("mixedform item %q has no result ptr", it.Label)
+		}
+	}
+	if m.Text == nil {
+		return nil, fmt.Errorf("mixedform has no text func")
+	}
+	out, err := m.Common.backend().MixedForm(ctx, &m.Common, crumbs, m.Text(), m.formHeight(), m.Items)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(out, "\n")
+	for i, it := range m.Items {
+		if i < len(lines) {
+			*it.Value = lines[i]
+		}
+	}
+	return m.NextSibling, nil
+}
+
+// mixedFormItemArgs lays items out as dialog expects:
+// label y x item y x flen ilen itype
+func mixedFormItemArgs(items []MixedFormItem) []string {
+	ret := []string{}
+	for _, it := range items {
+		itemX := it.X + len(it.Label) + 1
+		ret = append(ret,
+			it.Label, strconv.Itoa(it.Y), strconv.Itoa(it.X),
+			*it.Value, strconv.Itoa(it.Y), strconv.Itoa(itemX),
+			strconv.Itoa(it.FLen), strconv.Itoa(it.ILen), strconv.Itoa(it.IType))
+	}
+	return ret
+}
+
+// ProgramBox runs Program and renders whatever kind of progress it reports.
+// ExecBackend picks one dialog widget from Program's first update alone: a
+// --mixedgauge if it names a sub-task via Task, a plain --gauge if it has no
+// Log, or a scrolling --programbox log otherwise; later updates are shown
+// only as far as that widget can (a gauge ignores Log, a mixedgauge ignores
+// Status and Log, a log ignores Percent). See Progress and Task.
 type ProgramBox struct {
 	Common
 	Text    string
-	Program func(io.WriteCloser) error
+	Program func(Progress) error
 	Next    Dialog
 }
 
-func (m *ProgramBox) Run(crumbs string) (Dialog, error) {
+func (m *ProgramBox) Run(ctx context.Context, crumbs string) (Dialog, error) {
 	if m.Program == nil {
 		return nil, fmt.Errorf("programbox has no program callback set")
 	}
-	piperd, pipewr := io.Pipe()
-
-	// spawn the program
-	doneChan := make(chan error)
+	updates := make(chan ProgressUpdate)
+	doneChan := make(chan error, 1)
 	go func() {
-		doneChan <- m.Program(pipewr)
+		defer close(updates)
+		doneChan <- m.Program(updates)
 	}()
 
-	args := m.Common.runArgs()
-	args = append(args,
-		"--programbox", crumbs+"\\n"+m.Text,
-		strconv.Itoa(m.height()),
-		strconv.Itoa(m.width()))
-	_, err := runStdin(args, piperd)
+	_, err := m.Common.backend().ProgramBox(ctx, &m.Common, crumbs, m.Text, updates)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,49 @@
+package dialog
+
+import "testing"
+
+func TestParseCheckListResult(t *testing.T) {
+	tests := []struct {
+		name string
+		k    string
+		n    int
+		want map[int]bool
+	}{
+		{"empty string yields nothing", "", 3, map[int]bool{}},
+		{"single index", "1", 3, map[int]bool{1: true}},
+		{"multiple space-separated indices", "0 2", 3, map[int]bool{0: true, 2: true}},
+		{"out-of-range indices ignored", "0 5", 3, map[int]bool{0: true}},
+		{"non-numeric tokens ignored", "0 foo 1", 3, map[int]bool{0: true, 1: true}},
+		{"extra whitespace collapses", "  0   1  ", 3, map[int]bool{0: true, 1: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCheckListResult(tt.k, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseCheckListResult(%q, %d) = %v, want %v", tt.k, tt.n, got, tt.want)
+			}
+			for i, v := range tt.want {
+				if got[i] != v {
+					t.Errorf("parseCheckListResult(%q, %d)[%d] = %v, want %v", tt.k, tt.n, i, got[i], v)
+				}
+			}
+		})
+	}
+}
+
+func TestMixedFormItemArgs(t *testing.T) {
+	val := "hello"
+	items := []MixedFormItem{
+		{Label: "Name", Y: 1, X: 1, FLen: 10, ILen: 10, IType: 0, Value: &val},
+	}
+	got := mixedFormItemArgs(items)
+	want := []string{"Name", "1", "1", "hello", "1", "6", "10", "10", "0"}
+	if len(got) != len(want) {
+		t.Fatalf("mixedFormItemArgs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("mixedFormItemArgs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
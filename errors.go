@@ -0,0 +1,37 @@
+package dialog
+
+import "fmt"
+
+// dialog(1)'s own exit codes, exposed so callers can react to how a widget
+// was dismissed instead of treating every non-zero exit as fatal.
+const (
+	ExitOK     = 0
+	ExitCancel = 1
+	ExitHelp   = 2
+	ExitExtra  = 3
+	ExitEsc    = 255
+)
+
+// DialogError wraps a non-OK exit from a widget, whether it came from the
+// 'dialog' process exiting non-zero or NativeBackend's own Escape handling.
+type DialogError struct {
+	Code int
+}
+
+func (e *DialogError) Error() string {
+	return fmt.Sprintf("dialog: exited with code %d", e.Code)
+}
+
+// IsCancel reports whether the user dismissed the widget via its Cancel
+// button/action -- conventionally "go back one level" in a wizard.
+func (e *DialogError) IsCancel() bool { return e.Code == ExitCancel }
+
+// IsEsc reports whether the user pressed Escape -- conventionally "abort
+// the whole tree", unlike IsCancel.
+func (e *DialogError) IsEsc() bool { return e.Code == ExitEsc }
+
+// IsHelp reports whether the widget was dismissed via its Help button.
+func (e *DialogError) IsHelp() bool { return e.Code == ExitHelp }
+
+// IsExtra reports whether the widget was dismissed via its Extra button.
+func (e *DialogError) IsExtra() bool { return e.Code == ExitExtra }
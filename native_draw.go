@@ -0,0 +1,232 @@
+package dialog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+func drawText(s tcell.Screen, x, y int, style tcell.Style, text string) {
+	col := x
+	for _, r := range text {
+		if r == '\n' {
+			y++
+			col = x
+			continue
+		}
+		s.SetContent(col, y, r, nil, style)
+		col++
+	}
+}
+
+func drawFrame(s tcell.Screen, title string) {
+	s.Clear()
+	_, h := s.Size()
+	drawText(s, 1, 0, tcell.StyleDefault.Bold(true), title)
+	drawText(s, 1, h-1, tcell.StyleDefault, "Enter=OK  Esc=Abort  Ctrl-C=Cancel")
+}
+
+func drawBox(s tcell.Screen, title, text string) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	s.Show()
+}
+
+func drawMenu(s tcell.Screen, title, text string, opts []MenuOption, selected int) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	for i, mo := range opts {
+		style := tcell.StyleDefault
+		if i == selected {
+			style = style.Reverse(true)
+		}
+		drawText(s, 1, 4+i, style, mo.Key+"  "+mo.Text)
+	}
+	s.Show()
+}
+
+func drawInput(s tcell.Screen, title, text, value string) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	drawText(s, 1, 4, tcell.StyleDefault.Underline(true), value+" ")
+	s.Show()
+}
+
+func drawCheckList(s tcell.Screen, title, text string, items []CheckListItem, checked []bool, cursor int) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	for i, it := range items {
+		style := tcell.StyleDefault
+		if i == cursor {
+			style = style.Reverse(true)
+		}
+		mark := "[ ]"
+		if checked[i] {
+			mark = "[x]"
+		}
+		drawText(s, 1, 4+i, style, mark+" "+it.Name)
+	}
+	s.Show()
+}
+
+func drawConfirm(s tcell.Screen, title, text string, yes bool) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	yesStyle, noStyle := tcell.StyleDefault, tcell.StyleDefault
+	if yes {
+		yesStyle = yesStyle.Reverse(true)
+	} else {
+		noStyle = noStyle.Reverse(true)
+	}
+	drawText(s, 1, 4, yesStyle, "< Yes >")
+	drawText(s, 10, 4, noStyle, "< No >")
+	s.Show()
+}
+
+// visibleIndices returns the indices of items whose Name contains filter,
+// case-insensitively. An empty filter matches everything.
+func visibleIndices(items []CheckListItem, filter string) []int {
+	out := []int{}
+	filter = strings.ToLower(filter)
+	for i, it := range items {
+		if filter == "" || strings.Contains(strings.ToLower(it.Name), filter) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func drawMultiSelect(s tcell.Screen, title, text string, items []CheckListItem, checked []bool, visible []int, cursor int, filter string) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	drawText(s, 1, 3, tcell.StyleDefault, "Filter: "+filter)
+	for row, idx := range visible {
+		style := tcell.StyleDefault
+		if row == cursor {
+			style = style.Reverse(true)
+		}
+		mark := "[ ]"
+		if checked[idx] {
+			mark = "[x]"
+		}
+		drawText(s, 1, 5+row, style, mark+" "+items[idx].Name)
+	}
+	s.Show()
+}
+
+func drawMixedForm(s tcell.Screen, title, text string, items []MixedFormItem, values []string, cursor int) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	for i, it := range items {
+		style := tcell.StyleDefault
+		if i == cursor {
+			style = style.Reverse(true)
+		}
+		display := values[i]
+		if it.IType == 2 {
+			display = strings.Repeat("*", len(display))
+		}
+		drawText(s, 1, 4+i, tcell.StyleDefault, it.Label+":")
+		drawText(s, 2+len(it.Label), 4+i, style, display+" ")
+	}
+	s.Show()
+}
+
+// drawProgress renders whatever the current update batch carries: an
+// overall bar, one bar per named task, and a tail of log lines.
+func drawProgress(s tcell.Screen, title, text string, overall int, order []string, tasks map[string]int, logLines []string) {
+	drawFrame(s, title)
+	drawText(s, 1, 2, tcell.StyleDefault, text)
+	drawText(s, 1, 4, tcell.StyleDefault, progressBar(overall))
+
+	row := 6
+	for _, name := range order {
+		drawText(s, 1, row, tcell.StyleDefault, name+": "+progressBar(tasks[name]))
+		row++
+	}
+
+	_, h := s.Size()
+	maxLines := h - row - 1
+	if maxLines > 0 && len(logLines) > 0 {
+		tail := logLines
+		if len(tail) > maxLines {
+			tail = tail[len(tail)-maxLines:]
+		}
+		for i, l := range tail {
+			drawText(s, 1, row+1+i, tcell.StyleDefault, l)
+		}
+	}
+	s.Show()
+}
+
+func progressBar(percent int) string {
+	const width = 40
+	filled := width * percent / 100
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + fmt.Sprintf("] %3d%%", percent)
+}
+
+// pollEventCtx polls s for the next event, but returns ctx.Err() as soon as
+// ctx is canceled. s.PollEvent() itself can't be interrupted, so a canceled
+// poll's goroutine is left to exit on the screen's next event (or its Fini)
+// rather than leaking forever.
+func pollEventCtx(ctx context.Context, s tcell.Screen) (tcell.Event, error) {
+	evChan := make(chan tcell.Event, 1)
+	go func() {
+		evChan <- s.PollEvent()
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case ev := <-evChan:
+		return ev, nil
+	}
+}
+
+func readKeyCtx(ctx context.Context, s tcell.Screen) (tcell.Key, error) {
+	for {
+		ev, err := pollEventCtx(ctx, s)
+		if err != nil {
+			return 0, err
+		}
+		if kev, ok := ev.(*tcell.EventKey); ok {
+			return kev.Key(), nil
+		}
+	}
+}
+
+func waitForKeyCtx(ctx context.Context, s tcell.Screen, keys ...tcell.Key) (tcell.Key, error) {
+	for {
+		k, err := readKeyCtx(ctx, s)
+		if err != nil {
+			return 0, err
+		}
+		for _, want := range keys {
+			if k == want {
+				return k, nil
+			}
+		}
+	}
+}
+
+func sleepOrKeyCtx(ctx context.Context, s tcell.Screen, seconds int) error {
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(time.Duration(seconds) * time.Second)
+		close(done)
+	}()
+	evChan := make(chan tcell.Event, 1)
+	go func() {
+		evChan <- s.PollEvent()
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	case <-evChan:
+		return nil
+	}
+}
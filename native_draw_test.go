@@ -0,0 +1,35 @@
+package dialog
+
+import "testing"
+
+func TestVisibleIndices(t *testing.T) {
+	on, off := true, false
+	items := []CheckListItem{
+		{Name: "Apple", Value: &on},
+		{Name: "Banana", Value: &off},
+		{Name: "Cherry", Value: &on},
+	}
+	tests := []struct {
+		name   string
+		filter string
+		want   []int
+	}{
+		{"empty filter matches everything", "", []int{0, 1, 2}},
+		{"case-insensitive substring match", "ban", []int{1}},
+		{"matches multiple items", "a", []int{0, 1}},
+		{"no match returns empty", "zzz", []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := visibleIndices(items, tt.filter)
+			if len(got) != len(tt.want) {
+				t.Fatalf("visibleIndices(%q) = %v, want %v", tt.filter, got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("visibleIndices(%q)[%d] = %d, want %d", tt.filter, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package dialog
+
+// Progress reporting for ProgramBox: a typed channel instead of a raw
+// io.WriteCloser, so a caller doesn't need to know dialog's stdin protocol
+// for --gauge/--mixedgauge/--programbox, and Task so multi-step work can
+// report one coherent overall percentage.
+
+// ProgressUpdate is one report from a ProgramBox's Program callback.
+// Percent is 0-100, or -1 if this update carries no overall percent.
+// Task names a sub-task (for a --mixedgauge); empty means the overall job.
+// Log, if set, is appended to a scrolling log instead of replacing a gauge;
+// under ExecBackend that log exists only when ProgramBox chose --programbox.
+type ProgressUpdate struct {
+	Percent int
+	Status  string
+	Log     string
+	Task    string
+}
+
+// Progress is the send-only side of the channel a Program callback reports
+// progress on.
+type Progress chan<- ProgressUpdate
+
+func clampPercent(p int) int {
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return p
+}
+
+// Task composes a long-running job out of weighted children so it can
+// report one coherent overall percentage instead of forcing every caller
+// to do that math inline.
+type Task struct {
+	Name     string
+	Weight   float64
+	Percent  int
+	Children []*Task
+}
+
+// NewTask creates a leaf or parent task. Weight only matters to a parent
+// averaging its children; a weight of 0 is treated as 1.
+func NewTask(name string, weight float64) *Task {
+	return &Task{Name: name, Weight: weight}
+}
+
+// AddChild attaches child to t and returns it, so a tree can be built
+// inline: root.AddChild(NewTask("download", 3)).
+func (t *Task) AddChild(child *Task) *Task {
+	t.Children = append(t.Children, child)
+	return child
+}
+
+// Overall returns t's percentage: its own Percent if it has no children,
+// otherwise the weighted average of its children's Overall().
+func (t *Task) Overall() int {
+	if len(t.Children) == 0 {
+		return clampPercent(t.Percent)
+	}
+	var totalWeight, sum float64
+	for _, c := range t.Children {
+		w := c.Weight
+		if w <= 0 {
+			w = 1
+		}
+		totalWeight += w
+		sum += w * float64(c.Overall())
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return int(sum / totalWeight)
+}
+
+// Send reports t's current Overall() on p, tagged with t.Name so a
+// --mixedgauge backend can track it alongside its siblings.
+func (t *Task) Send(p Progress, status string) {
+	p <- ProgressUpdate{Percent: t.Overall(), Status: status, Task: t.Name}
+}
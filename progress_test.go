@@ -0,0 +1,92 @@
+package dialog
+
+import "testing"
+
+func TestClampPercent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   int
+		want int
+	}{
+		{"negative clamps to zero", -5, 0},
+		{"zero passes through", 0, 0},
+		{"in range passes through", 42, 42},
+		{"exactly 100 passes through", 100, 100},
+		{"over 100 clamps to 100", 150, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clampPercent(tt.in); got != tt.want {
+				t.Errorf("clampPercent(%d) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskOverall(t *testing.T) {
+	tests := []struct {
+		name string
+		task *Task
+		want int
+	}{
+		{
+			name: "leaf returns its own percent",
+			task: &Task{Percent: 40},
+			want: 40,
+		},
+		{
+			name: "leaf clamps an out-of-range percent",
+			task: &Task{Percent: 150},
+			want: 100,
+		},
+		{
+			name: "equal-weight children average",
+			task: func() *Task {
+				root := NewTask("root", 0)
+				root.AddChild(&Task{Percent: 0})
+				root.AddChild(&Task{Percent: 100})
+				return root
+			}(),
+			want: 50,
+		},
+		{
+			name: "weighted children favor the heavier one",
+			task: func() *Task {
+				root := NewTask("root", 0)
+				root.AddChild(&Task{Percent: 0, Weight: 1})
+				root.AddChild(&Task{Percent: 100, Weight: 3})
+				return root
+			}(),
+			want: 75,
+		},
+		{
+			name: "zero weight treated as one",
+			task: func() *Task {
+				root := NewTask("root", 0)
+				root.AddChild(&Task{Percent: 20, Weight: 0})
+				root.AddChild(&Task{Percent: 80, Weight: 0})
+				return root
+			}(),
+			want: 50,
+		},
+		{
+			name: "nested children recurse",
+			task: func() *Task {
+				root := NewTask("root", 0)
+				mid := root.AddChild(NewTask("mid", 1))
+				mid.AddChild(&Task{Percent: 0})
+				mid.AddChild(&Task{Percent: 100})
+				root.AddChild(&Task{Percent: 100})
+				return root
+			}(),
+			want: 75,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.Overall(); got != tt.want {
+				t.Errorf("Overall() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package dialog
+
+// Runner drives a Dialog tree from a pre-recorded set of answers instead of
+// a real terminal, so flows built on this package can be exercised
+// unattended (tests, provisioning pipelines) or replayed from a capture.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Answer is one recorded response. Value is used by Menu and InputBox;
+// Bools is used by CheckListBox, keyed by CheckListItem.Name.
+type Answer struct {
+	Value string          `json:"value,omitempty" yaml:"value,omitempty"`
+	Bools map[string]bool `json:"bools,omitempty" yaml:"bools,omitempty"`
+}
+
+// Answers maps a widget's stable path (breadcrumbs + Common.Key) to its
+// recorded Answer.
+type Answers map[string]Answer
+
+// LoadAnswers parses an answers source, trying JSON first and falling back
+// to YAML so either format works unmodified.
+func LoadAnswers(data []byte) (Answers, error) {
+	a := Answers{}
+	if err := json.Unmarshal(data, &a); err == nil {
+		return a, nil
+	}
+	if err := yaml.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// LoadAnswersFile reads and parses an answers file.
+func LoadAnswersFile(path string) (Answers, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadAnswers(data)
+}
+
+// SaveAnswersFile writes answers as indented JSON, suitable for a "record
+// mode" capture that can later be fed back in as an answers file.
+func SaveAnswersFile(path string, answers Answers) error {
+	data, err := json.MarshalIndent(answers, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// backendSetter is implemented by every widget pointer via its embedded
+// Common.
+type backendSetter interface {
+	setBackend(Backend)
+}
+
+// Runner wraps a root Dialog and answers every widget in its tree from
+// Answers instead of rendering it. It also implements Backend directly, so
+// it can be driven one widget at a time via Common.Backend if callers
+// prefer that to Run.
+type Runner struct {
+	Root    Dialog
+	Answers Answers
+	// Record, if non-nil, is populated with the answer taken from Answers
+	// for every widget visited that had one, so a driven run can be saved
+	// for later replay. Widgets that fell back to their default or current
+	// value are not recorded.
+	Record Answers
+}
+
+// NewRunner builds a Runner for root driven by answers.
+func NewRunner(root Dialog, answers Answers) *Runner {
+	return &Runner{Root: root, Answers: answers}
+}
+
+func (r *Runner) path(crumbs, key string) string {
+	return crumbs + "::" + key
+}
+
+func (r *Runner) remember(key string, a Answer) {
+	if r.Record != nil {
+		r.Record[key] = a
+	}
+}
+
+// Run drives the dialog tree to completion, substituting itself as the
+// Backend of every widget it visits, and returns the final (nil) Dialog or
+// the error that stopped the walk.
+func (r *Runner) Run(ctx context.Context) (Dialog, error) {
+	var current Dialog = r.Root
+	crumbs := ""
+	for current != nil {
+		if bs, ok := current.(backendSetter); ok {
+			bs.setBackend(r)
+		}
+		next, err := current.Run(ctx, crumbs)
+		if err != nil {
+			return nil, err
+		}
+		if child, ok := next.(ChildDialog); ok {
+			if child.Crumb != nil {
+				crumbs = crumbs + "\\n" + child.Crumb()
+			}
+			current = child.Dialog
+			continue
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func (Runner) MsgBox(ctx context.Context, c *Common, text string) error {
+	return nil
+}
+
+func (Runner) Pause(ctx context.Context, c *Common, crumbs, text string, seconds int) error {
+	return nil
+}
+
+func (r *Runner) Menu(ctx context.Context, c *Common, crumbs, text string, menuHeight int, defaultKey string, opts []MenuOption) (string, error) {
+	key := r.path(crumbs, c.Key)
+	if a, ok := r.Answers[key]; ok && a.Value != "" {
+		r.remember(key, a)
+		return a.Value, nil
+	}
+	if defaultKey != "" {
+		return defaultKey, nil
+	}
+	if len(opts) > 0 {
+		return opts[0].Key, nil
+	}
+	return "", fmt.Errorf("dialog: no answer for menu %q and no default or options", key)
+}
+
+func (r *Runner) InputBox(ctx context.Context, c *Common, crumbs, text, value string) (string, error) {
+	key := r.path(crumbs, c.Key)
+	if a, ok := r.Answers[key]; ok {
+		r.remember(key, a)
+		return a.Value, nil
+	}
+	return value, nil
+}
+
+func (r *Runner) CheckListBox(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem) (string, error) {
+	key := r.path(crumbs, c.Key)
+	a, ok := r.Answers[key]
+	out := ""
+	for i, item := range items {
+		on := *item.Value
+		if ok {
+			if v, set := a.Bools[item.Name]; set {
+				on = v
+			}
+		}
+		if on {
+			out += strconv.Itoa(i) + " "
+		}
+	}
+	if ok {
+		r.remember(key, a)
+	}
+	return out, nil
+}
+
+func (Runner) ProgramBox(ctx context.Context, c *Common, crumbs, text string, updates <-chan ProgressUpdate) (string, error) {
+	for range updates {
+	}
+	return "", nil
+}
+
+func (r *Runner) Confirm(ctx context.Context, c *Common, crumbs, text string, defaultYes bool) (bool, error) {
+	key := r.path(crumbs, c.Key)
+	if a, ok := r.Answers[key]; ok {
+		r.remember(key, a)
+		return a.Value == "yes", nil
+	}
+	return defaultYes, nil
+}
+
+func (r *Runner) Password(ctx context.Context, c *Common, crumbs, text string) (string, error) {
+	key := r.path(crumbs, c.Key)
+	if a, ok := r.Answers[key]; ok {
+		r.remember(key, a)
+		return a.Value, nil
+	}
+	return "", nil
+}
+
+func (r *Runner) MultiSelect(ctx context.Context, c *Common, crumbs, text string, items []CheckListItem, filterable bool) (string, error) {
+	return r.CheckListBox(ctx, c, crumbs, text, items)
+}
+
+func (r *Runner) Editor(ctx context.Context, c *Common, crumbs, text, value string) (string, error) {
+	key := r.path(crumbs, c.Key)
+	if a, ok := r.Answers[key]; ok {
+		r.remember(key, a)
+		return a.Value, nil
+	}
+	return value, nil
+}
+
+// MixedForm resolves each field as "<crumbs>::<Common.Key>/<field index>",
+// since a form's fields don't have their own Common to carry a Key.
+func (r *Runner) MixedForm(ctx context.Context, c *Common, crumbs, text string, formHeight int, items []MixedFormItem) (string, error) {
+	base := r.path(crumbs, c.Key)
+	lines := make([]string, len(items))
+	for i, it := range items {
+		lines[i] = *it.Value
+		if a, ok := r.Answers[base+"/"+strconv.Itoa(i)]; ok {
+			lines[i] = a.Value
+			r.remember(base+"/"+strconv.Itoa(i), a)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
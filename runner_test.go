@@ -0,0 +1,54 @@
+package dialog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunnerWalksTreeFromAnswers(t *testing.T) {
+	var name string
+
+	root := &Menu{
+		Common: Common{Key: "root"},
+		Text:   func() string { return "pick one" },
+		Options: func() ([]MenuOption, error) {
+			return []MenuOption{
+				{Key: "signup", Text: "Sign up", Next: &InputBox{
+					Common: Common{Key: "name"},
+					Text:   func() string { return "your name?" },
+					Value:  &name,
+					NextSibling: &Confirm{
+						Common:  Common{Key: "subscribe"},
+						Text:    func() string { return "subscribe to updates?" },
+						Default: false,
+						Yes:     nil,
+						No:      nil,
+					},
+				}},
+			}, nil
+		},
+	}
+
+	answers := Answers{
+		"::root":                {Value: "signup"},
+		"\\nSign up::name":      {Value: "Ada"},
+		"\\nSign up::subscribe": {Value: "yes"},
+	}
+	record := Answers{}
+	runner := NewRunner(root, answers)
+	runner.Record = record
+
+	final, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if final != nil {
+		t.Fatalf("Run() final dialog = %v, want nil (Confirm's Yes)", final)
+	}
+	if name != "Ada" {
+		t.Errorf("name = %q, want %q", name, "Ada")
+	}
+	if len(record) != len(answers) {
+		t.Errorf("Record has %d entries, want %d", len(record), len(answers))
+	}
+}
@@ -0,0 +1,60 @@
+package dialog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// exitCode extracts a process exit status from the error returned by
+// exec.Cmd.Run, the same way callers have always had to when they care
+// about more than success/failure (e.g. dialog's yes=0/no=1 convention).
+func exitCode(err error) (int, bool) {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus(), true
+		}
+	}
+	return 0, false
+}
+
+// runEditor writes value to a temp file, opens it in $EDITOR (falling back
+// to vi), and returns the edited contents. Used by the Editor widget
+// regardless of which Backend is otherwise in effect, since handing the
+// terminal to $EDITOR is the same operation either way.
+func runEditor(ctx context.Context, value string) (string, error) {
+	f, err := ioutil.TempFile("", "dialog-editor-")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	if _, err := f.WriteString(value); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.CommandContext(ctx, editor, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	edited, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(edited), nil
+}
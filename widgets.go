@@ -0,0 +1,152 @@
+package dialog
+
+// Widgets beyond what dialog(1) exposes natively, layered on the same
+// Common/Backend plumbing as MsgBox, Menu, InputBox and friends so they
+// compose in the same menu trees.
+
+import (
+	"context"
+	"fmt"
+)
+
+// Confirm is a yes/no prompt with a default answer.
+type Confirm struct {
+	Common
+	Text    func() string
+	Default bool
+	Yes     Dialog
+	No      Dialog
+}
+
+func (m *Confirm) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	if m.Text == nil {
+		return nil, fmt.Errorf("confirm has no text func")
+	}
+	yes, err := m.Common.backend().Confirm(ctx, &m.Common, crumbs, m.Text(), m.Default)
+	if err != nil {
+		return nil, err
+	}
+	if yes {
+		return m.Yes, nil
+	}
+	return m.No, nil
+}
+
+// Password prompts for a value that is never echoed back to the terminal
+// or to logs; only the caller's *Value pointer ever sees the plaintext.
+type Password struct {
+	Common
+	Text        func() string
+	Value       *string
+	NextSibling Dialog
+}
+
+func (m *Password) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	if m.Value == nil {
+		return nil, fmt.Errorf("password has no result ptr")
+	}
+	if m.Text == nil {
+		return nil, fmt.Errorf("password has no text func")
+	}
+	v, err := m.Common.backend().Password(ctx, &m.Common, crumbs, m.Text())
+	if err != nil {
+		return nil, err
+	}
+	*m.Value = v
+	return m.NextSibling, nil
+}
+
+// MultiSelect is a CheckListBox that additionally supports incremental
+// filter-as-you-type, which matters once Items runs into the hundreds.
+// ExecBackend has no live filtering and renders it as a plain checklist.
+type MultiSelect struct {
+	Common
+	Text        func() string
+	Items       []CheckListItem
+	Filterable  bool
+	NextSibling Dialog
+}
+
+func (m *MultiSelect) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	for _, item := range m.Items {
+		if item.Value == nil {
+			return nil, fmt.Errorf("multiselect has no result ptr")
+		}
+	}
+	if m.Text == nil {
+		return nil, fmt.Errorf("multiselect has no text func")
+	}
+	k, err := m.Common.backend().MultiSelect(ctx, &m.Common, crumbs, m.Text(), m.Items, m.Filterable)
+	if err != nil {
+		return nil, err
+	}
+	setIndices := parseCheckListResult(k, len(m.Items))
+	for i, item := range m.Items {
+		*item.Value = setIndices[i]
+	}
+	return m.NextSibling, nil
+}
+
+// Editor hands the terminal to $EDITOR against a temp file seeded with
+// *Value, then stores the edited buffer back into it.
+type Editor struct {
+	Common
+	Text        func() string
+	Value       *string
+	NextSibling Dialog
+}
+
+func (m *Editor) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	if m.Value == nil {
+		return nil, fmt.Errorf("editor has no result ptr")
+	}
+	text := ""
+	if m.Text != nil {
+		text = m.Text()
+	}
+	v, err := m.Common.backend().Editor(ctx, &m.Common, crumbs, text, *m.Value)
+	if err != nil {
+		return nil, err
+	}
+	*m.Value = v
+	return m.NextSibling, nil
+}
+
+// Form is a --mixedform whose fields are all plain text, i.e. IType is
+// forced to 0 regardless of what Items set.
+type Form struct {
+	Common
+	Text        func() string
+	FormHeight  int
+	Items       []MixedFormItem
+	NextSibling Dialog
+}
+
+func (m *Form) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	return asMixedForm(m.Common, m.Text, m.FormHeight, m.Items, m.NextSibling, 0).Run(ctx, crumbs)
+}
+
+// asMixedForm builds the *MixedForm that backs Form and PasswordForm, with
+// every item's IType forced to itype regardless of what the caller set.
+func asMixedForm(common Common, text func() string, formHeight int, items []MixedFormItem, nextSibling Dialog, itype int) *MixedForm {
+	out := make([]MixedFormItem, len(items))
+	for i, it := range items {
+		it.IType = itype
+		out[i] = it
+	}
+	return &MixedForm{Common: common, Text: text, FormHeight: formHeight, Items: out, NextSibling: nextSibling}
+}
+
+// PasswordForm is a --mixedform whose fields are all masked, i.e. IType is
+// forced to 2 regardless of what Items set.
+type PasswordForm struct {
+	Common
+	Text        func() string
+	FormHeight  int
+	Items       []MixedFormItem
+	NextSibling Dialog
+}
+
+func (m *PasswordForm) Run(ctx context.Context, crumbs string) (Dialog, error) {
+	return asMixedForm(m.Common, m.Text, m.FormHeight, m.Items, m.NextSibling, 2).Run(ctx, crumbs)
+}